@@ -0,0 +1,36 @@
+package config
+
+import (
+	lcrypto "github.com/libp2p/go-libp2p-core/crypto"
+)
+
+// Config is the top-level shape loaded from a config file (and merged with
+// the packaged defaults in mergeDefaults).
+type Config struct {
+	Host Host
+}
+
+// Host holds every host-level setting an operator can declare, from the
+// listen/bootstrap addresses through to the transport/muxer/security option
+// chains parsed by pkg/host.
+type Host struct {
+	// Priv is parsed from PrivPEM by loadAndSavePriv; it isn't itself present
+	// in the config file.
+	Priv lcrypto.PrivKey `json:"-"`
+
+	PrivPEM string
+
+	Listens []string
+	Peers   []string
+
+	Transports []string
+	Muxers     [][]string
+
+	// Security is the ordered list of security transport options to chain,
+	// e.g. ["tls", "noise"]; parsed by pkg/host's parseSecurityOptions.
+	Security []string
+
+	// Filters are the operator-declared default filters a light node opens
+	// against each bootstrap peer; see FilterSpec.
+	Filters []FilterSpec
+}
@@ -0,0 +1,13 @@
+package config
+
+// FilterSpec is an operator-declared default filter, parsed straight out of
+// the config file by parseConfigFile (via viper/mapstructure) and merged by
+// mergeDefaults like the rest of Host.*. It mirrors host.FilterSpec; it's
+// duplicated here rather than imported so this package doesn't need to
+// depend on pkg/host.
+type FilterSpec struct {
+	Topic            string
+	MessageIDPrefix  string
+	SenderAllowlist  []string
+	PayloadSubstring string
+}
@@ -49,12 +49,12 @@ func parseConfigFile(conf *Config, confLoc string) error {
 	v.AddConfigPath(".")
 
 	if err := v.ReadInConfig(); err != nil {
-		logger.Errorf("err reading configuration file: %s\n%v", confLoc, err)
+		logger.Error("err reading configuration file", "confLoc", confLoc, "err", err)
 		return err
 	}
 
 	if err := v.Unmarshal(conf); err != nil {
-		logger.Errorf("err unmarshaling config\n%v", err)
+		logger.Error("err unmarshaling config", "err", err)
 		return err
 	}
 
@@ -80,13 +80,13 @@ func loadDefaultPriv() ([]byte, error) {
 func loadAndSavePriv(conf *Config) error {
 	privB, err := loadPriv(conf.Host.PrivPEM)
 	if err != nil {
-		logger.Errorf("err loading private key file:\n%v", err)
+		logger.Error("err loading private key file", "err", err)
 		return err
 	}
 
 	priv, err := parsePrivateKey(privB)
 	if err != nil {
-		logger.Errorf("err parsing private key:\n%v", err)
+		logger.Error("err parsing private key", "err", err)
 		return err
 	}
 
@@ -97,14 +97,14 @@ func loadAndSavePriv(conf *Config) error {
 func loadPriv(loc string) ([]byte, error) {
 	privateKeyFile, err := os.Open(loc)
 	if err != nil {
-		logger.Errorf("err loading private key pem file: %s\n%v", loc, err)
+		logger.Error("err loading private key pem file", "loc", loc, "err", err)
 		return nil, err
 	}
 	defer privateKeyFile.Close()
 
 	pemfileinfo, err := privateKeyFile.Stat()
 	if err != nil {
-		logger.Errorf("err statting private key file:\n%v", err)
+		logger.Error("err statting private key file", "err", err)
 		return nil, err
 	}
 	var size int64 = pemfileinfo.Size()
@@ -118,7 +118,7 @@ func loadPriv(loc string) ([]byte, error) {
 func parseDefaultPriv() (lcrypto.PrivKey, error) {
 	defaultPriv, err := loadDefaultPriv()
 	if err != nil {
-		logger.Errorf("err loading default private key:\n%v", err)
+		logger.Error("err loading default private key", "err", err)
 		return nil, err
 	}
 
@@ -128,20 +128,20 @@ func parseDefaultPriv() (lcrypto.PrivKey, error) {
 func parsePrivateKey(privB []byte) (lcrypto.PrivKey, error) {
 	data, _ := pem.Decode(privB)
 	if data == nil {
-		logger.Error("err decoding default PEM file. Nil data block")
+		logger.Error("err decoding default PEM file: nil data block")
 		return nil, errors.New("err decoding default PEM file")
 	}
 
 	cPriv, err := x509.ParsePKCS8PrivateKey(data.Bytes)
 	if err != nil {
-		logger.Errorf("err parsing private key bytes:\n%v", err)
+		logger.Error("err parsing private key bytes", "err", err)
 		return nil, err
 	}
 
 	// TODO: remove ASAP
 	priv, _, err := acrypto.KeyPairFromKey(cPriv)
 	if err != nil {
-		logger.Errorf("err generating lcrypto priv key:\n%v", err)
+		logger.Error("err generating lcrypto priv key", "err", err)
 		return nil, err
 	}
 
@@ -151,18 +151,18 @@ func parsePrivateKey(privB []byte) (lcrypto.PrivKey, error) {
 func parseDefaults(conf *Config) error {
 	defaultConfig, err := loadDefaultConfig()
 	if err != nil {
-		logger.Errorf("err loading default config:\n%v", err)
+		logger.Error("err loading default config", "err", err)
 		return err
 	}
 
 	if err := json.Unmarshal(defaultConfig, conf); err != nil {
-		logger.Errorf("err unmarshaling config\n%v", err)
+		logger.Error("err unmarshaling config", "err", err)
 		return err
 	}
 
 	priv, err := parseDefaultPriv()
 	if err != nil {
-		logger.Errorf("err parsing default private key:\n%v", err)
+		logger.Error("err parsing default private key", "err", err)
 		return err
 	}
 	conf.Host.Priv = priv
@@ -187,7 +187,10 @@ func mergeDefaults(conf, defaults *Config) {
 	if len(conf.Host.Muxers) == 0 {
 		conf.Host.Muxers = defaults.Host.Muxers
 	}
-	if conf.Host.Security == "" {
+	if len(conf.Host.Security) == 0 {
 		conf.Host.Security = defaults.Host.Security
 	}
+	if len(conf.Host.Filters) == 0 {
+		conf.Host.Filters = defaults.Host.Filters
+	}
 }
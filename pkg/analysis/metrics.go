@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/agencyenterprise/gossip-host/pkg/analysis/types"
+	"github.com/agencyenterprise/gossip-host/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// messageIDBucketCount bounds how many distinct "message_id_bucket" label
+// values the exported histograms can take on. Message IDs are unbounded and
+// effectively unique per message, so using the raw ID as a label would grow
+// Prometheus's series cardinality without bound; instead every message ID
+// is hashed into one of messageIDBucketCount buckets, trading exact
+// per-message attribution for a metric that stays cheap to scrape and store.
+// The label is named message_id_bucket, not message_id, so operators don't
+// mistake it for per-message data: distinct message IDs collide into the
+// same bucket, so a single label value aggregates an arbitrary, unbounded
+// set of unrelated messages.
+const messageIDBucketCount = 16
+
+var (
+	messagePropagationHops = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "message_propagation_hops",
+		Help:    "Number of hops from the original sender to the last delivery of a message.",
+		Buckets: prometheus.LinearBuckets(0, 1, 20),
+	}, []string{"message_id_bucket"})
+
+	messageRMR = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "message_rmr",
+		Help:    "Relative message redundancy observed for a message.",
+		Buckets: prometheus.LinearBuckets(0, 0.5, 20),
+	}, []string{"message_id_bucket"})
+
+	messageTotalNanoTime = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "message_total_nano_time",
+		Help:    "Nanoseconds between the first and last observed delivery of a message.",
+		Buckets: prometheus.ExponentialBuckets(1000, 4, 16),
+	}, []string{"message_id_bucket"})
+)
+
+// messageIDBucketLabel maps messageID onto a small, fixed set of label
+// values (see messageIDBucketCount).
+func messageIDBucketLabel(messageID string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(messageID))
+	return strconv.Itoa(int(h.Sum32() % messageIDBucketCount))
+}
+
+// ObserveMetric records metric's fields against the message_propagation_hops,
+// message_rmr, and message_total_nano_time histograms. It's meant to be used
+// as (or wrapped by) a StreamingAnalyzer's onMetric callback, so live hosts
+// export network health without replaying their log file.
+func ObserveMetric(messageID string, metric *types.Metric) {
+	bucket := messageIDBucketLabel(messageID)
+
+	messagePropagationHops.WithLabelValues(bucket).Observe(float64(metric.LastDeliveryHop))
+	messageRMR.WithLabelValues(bucket).Observe(float64(metric.RelativeMessageRedundancy))
+	messageTotalNanoTime.WithLabelValues(bucket).Observe(float64(metric.TotalNanoTime))
+}
+
+// ServeMetrics starts an HTTP server on addr exposing the above histograms
+// at /metrics for Prometheus to scrape. It blocks until the server stops or
+// errors, so callers typically run it in its own goroutine.
+func ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	logger.Info("serving prometheus metrics", "addr", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// LiveMetrics ties a StreamingAnalyzer to ObserveMetric and ServeMetrics, so
+// a live host can export these histograms just by feeding it delivered
+// gossip messages, instead of only being able to compute them offline from
+// a replayed log file.
+type LiveMetrics struct {
+	analyzer *StreamingAnalyzer
+}
+
+// NewLiveMetrics builds a LiveMetrics whose StreamingAnalyzer reports every
+// completed group's metric to ObserveMetric. If addr is non-empty,
+// ServeMetrics is started on addr in its own goroutine so the histograms
+// are scrapable immediately; errors from it are logged, not returned, since
+// it runs for the lifetime of the process.
+func NewLiveMetrics(capacity int, addr string) *LiveMetrics {
+	lm := &LiveMetrics{analyzer: NewStreamingAnalyzer(capacity, ObserveMetric)}
+
+	if addr != "" {
+		go func() {
+			if err := ServeMetrics(addr); err != nil {
+				logger.Error("err serving metrics", "addr", addr, "err", err)
+			}
+		}()
+	}
+
+	return lm
+}
+
+// Feed hands one delivered gossip message to the underlying
+// StreamingAnalyzer. Call it from wherever a live host delivers gossip
+// messages, the same way host.Repair.Observe and host.FilterService.Dispatch
+// are called, so this host's network health is actually observable instead
+// of only computable offline.
+func (lm *LiveMetrics) Feed(msg *types.MessageLog) {
+	lm.analyzer.Feed(msg)
+}
+
+// EvictStale forwards to the underlying StreamingAnalyzer; see
+// StreamingAnalyzer.EvictStale. Callers typically run this periodically
+// from its own goroutine, the same way host.Repair.WatchStaleness is run.
+func (lm *LiveMetrics) EvictStale(cutoff time.Time) {
+	lm.analyzer.EvictStale(cutoff)
+}
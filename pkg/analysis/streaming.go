@@ -0,0 +1,274 @@
+package analysis
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/agencyenterprise/gossip-host/pkg/analysis/types"
+	"github.com/agencyenterprise/gossip-host/pkg/logger"
+)
+
+// errNotEnoughHosts mirrors calcRMR's "cannot calculate RMR with one host"
+// error: a group's metric isn't meaningful until at least two hosts have
+// seen the message.
+var errNotEnoughHosts = errors.New("not enough hosts to compute a metric yet")
+
+// DefaultStreamingCapacity bounds how many in-flight message-ID groups a
+// StreamingAnalyzer tracks at once before it starts evicting the
+// least-recently-touched one, so a live host's memory use doesn't grow
+// without bound.
+const DefaultStreamingCapacity = 10000
+
+// groupState is the partial, incrementally-updated state for a single
+// MessageID, equivalent to what buildMetricsFromSortedMessageLogs computes
+// from a fully buffered, sorted slice - but built up one MessageLog at a
+// time and readable at any point.
+type groupState struct {
+	messageID string
+
+	// depth maps HostID to its hop count from the original sender, replacing
+	// the recursive DFS over [][]string paths that calcLastDeliveryHop used
+	// to run.
+	depth map[string]uint
+	// pending buffers MessageLogs received before their SenderID's depth is
+	// known yet (arrival order isn't guaranteed in live mode); keyed by the
+	// SenderID being waited on.
+	pending  map[string][]*types.MessageLog
+	maxDepth uint
+
+	firstNanoTime int64
+	lastNanoTime  int64
+
+	totalMessages uint
+	uniqueHosts   map[string]struct{}
+
+	lastTouched time.Time
+}
+
+func newGroupState(messageID string) *groupState {
+	return &groupState{
+		messageID:   messageID,
+		depth:       make(map[string]uint),
+		pending:     make(map[string][]*types.MessageLog),
+		uniqueHosts: make(map[string]struct{}),
+	}
+}
+
+// feed folds one more MessageLog for this group into its running state.
+func (g *groupState) feed(msg *types.MessageLog) {
+	g.lastTouched = time.Now()
+
+	if g.totalMessages == 0 || msg.NanoTime < g.firstNanoTime {
+		g.firstNanoTime = msg.NanoTime
+	}
+	if msg.NanoTime > g.lastNanoTime {
+		g.lastNanoTime = msg.NanoTime
+	}
+	g.totalMessages++
+	g.uniqueHosts[msg.HostID] = struct{}{}
+	g.uniqueHosts[msg.SenderID] = struct{}{}
+
+	// note: the first message this group ever sees establishes its root;
+	// matches the batch algorithm's `sortedMessageLogs[0].SenderID`, but we
+	// can't sort since we only see one message at a time, so we just take
+	// whichever message happens to arrive first.
+	if _, ok := g.depth[msg.SenderID]; !ok && len(g.depth) == 0 {
+		g.depth[msg.SenderID] = 0
+	}
+
+	g.resolveDepth(msg)
+}
+
+// resolveDepth is the incremental replacement for the old
+// buildPathsForSenderID DFS: rather than recomputing every path from scratch, it
+// sets depth[H] = depth[S] + 1 the moment S's depth is known, and cascades
+// into any messages that were buffered waiting on H.
+func (g *groupState) resolveDepth(msg *types.MessageLog) {
+	// note: assumes a host only ever receives a message from a given sender
+	// once, same as the batch implementation.
+	if _, ok := g.depth[msg.HostID]; ok {
+		return
+	}
+
+	d, ok := g.depth[msg.SenderID]
+	if !ok {
+		g.pending[msg.SenderID] = append(g.pending[msg.SenderID], msg)
+		return
+	}
+
+	g.depth[msg.HostID] = d + 1
+	if d+1 > g.maxDepth {
+		g.maxDepth = d + 1
+	}
+
+	cascade := g.pending[msg.HostID]
+	delete(g.pending, msg.HostID)
+	for _, pending := range cascade {
+		g.resolveDepth(pending)
+	}
+}
+
+// metric reports this group's current state as a *types.Metric; callers can
+// call it as often as they like, including before the group has finished.
+func (g *groupState) metric() (*types.Metric, error) {
+	uniqueHosts := uint(len(g.uniqueHosts))
+	if uniqueHosts < 2 {
+		return nil, errNotEnoughHosts
+	}
+
+	return &types.Metric{
+		TotalNanoTime:             uint64(g.lastNanoTime - g.firstNanoTime),
+		RelativeMessageRedundancy: (float32(g.totalMessages) / float32(uniqueHosts-1)) - 1.0,
+		LastDeliveryHop:           g.maxDepth,
+	}, nil
+}
+
+// StreamingAnalyzer computes metrics online, one *types.MessageLog at a
+// time, instead of requiring the whole log file to be loaded and sorted
+// first. It can be fed from a bufio.Scanner for replay of an existing log
+// file, or have Feed called directly from a live host's gossip subscriber
+// hooks.
+type StreamingAnalyzer struct {
+	mu       sync.Mutex
+	capacity int
+	groups   map[string]*list.Element
+	order    *list.List // front = most recently touched
+	onMetric func(messageID string, metric *types.Metric)
+}
+
+// NewStreamingAnalyzer builds a StreamingAnalyzer bounded to capacity
+// in-flight message-ID groups; onMetric, if non-nil, is called once per
+// group with its final metric when that group is evicted (capacity or
+// staleness), not on every Feed - RMR and hop count only grow as more
+// messages arrive for a MessageID, so observing them mid-flight would record
+// a string of ever-increasing partial samples instead of one real one.
+func NewStreamingAnalyzer(capacity int, onMetric func(messageID string, metric *types.Metric)) *StreamingAnalyzer {
+	if capacity <= 0 {
+		capacity = DefaultStreamingCapacity
+	}
+
+	return &StreamingAnalyzer{
+		capacity: capacity,
+		groups:   make(map[string]*list.Element),
+		order:    list.New(),
+		onMetric: onMetric,
+	}
+}
+
+// Feed folds one MessageLog into its group's running state, evicting the
+// least-recently-touched group if this pushes the analyzer over capacity.
+func (a *StreamingAnalyzer) Feed(msg *types.MessageLog) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	el, ok := a.groups[msg.MessageID]
+	var g *groupState
+	if ok {
+		g = el.Value.(*groupState)
+		a.order.MoveToFront(el)
+	} else {
+		g = newGroupState(msg.MessageID)
+		a.groups[msg.MessageID] = a.order.PushFront(g)
+	}
+
+	g.feed(msg)
+
+	a.evictOverCapacity()
+}
+
+// evictOverCapacity drops the least-recently-touched group(s) until the
+// analyzer is back at or under capacity, reporting each one's final metric
+// to onMetric on its way out. Callers must hold a.mu.
+func (a *StreamingAnalyzer) evictOverCapacity() {
+	for a.order.Len() > a.capacity {
+		oldest := a.order.Back()
+		if oldest == nil {
+			return
+		}
+
+		g := oldest.Value.(*groupState)
+		logger.Warn("evicting stale message group, streaming analyzer at capacity", "messageID", g.messageID, "capacity", a.capacity)
+
+		a.order.Remove(oldest)
+		delete(a.groups, g.messageID)
+
+		a.reportMetric(g)
+	}
+}
+
+// reportMetric calls a.onMetric with g's final metric, if onMetric is set
+// and g ever saw enough hosts to produce one. Callers must hold a.mu.
+func (a *StreamingAnalyzer) reportMetric(g *groupState) {
+	if a.onMetric == nil {
+		return
+	}
+
+	if metric, err := g.metric(); err == nil {
+		a.onMetric(g.messageID, metric)
+	}
+}
+
+// FeedScanner feeds every relevant line from scanner into the analyzer, for
+// replaying an existing log file in streaming mode instead of batch-loading
+// it with buildMetricsFromScanner.
+func (a *StreamingAnalyzer) FeedScanner(scanner *bufio.Scanner) error {
+	for scanner.Scan() {
+		msgLog, err := parseLogLine(scanner.Bytes())
+		if err != nil {
+			logger.Error("err parsing log line", "line", scanner.Text(), "err", err)
+			return err
+		}
+
+		if msgLog == nil {
+			continue
+		}
+
+		a.Feed(msgLog)
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Error("scanner error", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// Len reports how many message-ID groups are currently tracked.
+func (a *StreamingAnalyzer) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.order.Len()
+}
+
+// EvictStale drops every tracked group whose last touch was before cutoff,
+// e.g. called periodically with time.Now().Add(-staleAfter) so groups that
+// will never see another message (a peer dropped the rest of the flood)
+// don't sit in memory until capacity forces them out, reporting each one's
+// final metric to onMetric on its way out same as evictOverCapacity. Groups
+// are stored most-recently-touched first, so this stops at the first group
+// still fresh enough to keep.
+func (a *StreamingAnalyzer) EvictStale(cutoff time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		oldest := a.order.Back()
+		if oldest == nil {
+			return
+		}
+
+		g := oldest.Value.(*groupState)
+		if g.lastTouched.After(cutoff) {
+			return
+		}
+
+		a.order.Remove(oldest)
+		delete(a.groups, g.messageID)
+
+		a.reportMetric(g)
+	}
+}
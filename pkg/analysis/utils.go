@@ -3,7 +3,6 @@ package analysis
 import (
 	"bufio"
 	"errors"
-	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -16,7 +15,7 @@ import (
 func loadLogFile(logLoc string) (*bufio.Scanner, func() error, error) {
 	file, err := os.Open(logLoc)
 	if err != nil {
-		logger.Errorf("err opening log file at %s:\n%v", logLoc, err)
+		logger.Error("err opening log file", "logLoc", logLoc, "err", err)
 		return nil, nil, err
 	}
 
@@ -26,7 +25,7 @@ func loadLogFile(logLoc string) (*bufio.Scanner, func() error, error) {
 func buildMetricsFromScanner(scanner *bufio.Scanner) ([]*types.Metric, error) {
 	messageLogs, err := buildMessageLogsFromScanner(scanner)
 	if err != nil {
-		logger.Errorf("err building message logs:\n%v", err)
+		logger.Error("err building message logs", "err", err)
 		return nil, err
 	}
 
@@ -42,7 +41,7 @@ func buildMessageLogsFromScanner(scanner *bufio.Scanner) ([]*types.MessageLog, e
 		// note: is there a way to prevent allocation of these each loop?
 		msgLog, err := parseLogLine(scanner.Bytes())
 		if err != nil {
-			logger.Errorf("err parsing log line %s:\n%v", scanner.Text(), err)
+			logger.Error("err parsing log line", "line", scanner.Text(), "err", err)
 			return nil, err
 		}
 
@@ -55,7 +54,7 @@ func buildMessageLogsFromScanner(scanner *bufio.Scanner) ([]*types.MessageLog, e
 	}
 
 	if err := scanner.Err(); err != nil {
-		logger.Errorf("scanner error:\n%v", err)
+		logger.Error("scanner error", "err", err)
 		return nil, err
 	}
 
@@ -73,7 +72,7 @@ func parseLogLine(logLine []byte) (*types.MessageLog, error) {
 
 	data := strings.Split(line, ",")
 	if len(data) != 6 {
-		logger.Errorf("improperly formatted log line %s; expected length == 6, received %d", line, len(data))
+		logger.Error("improperly formatted log line", "line", line, "expectedFields", 6, "gotFields", len(data))
 		return nil, types.ErrImproperlyFormattedLogLine
 	}
 
@@ -91,9 +90,13 @@ func buildMetricsFromMessageLogs(messageLogs []*types.MessageLog) ([]*types.Metr
 func buildMetricsFromSortedMessageLogsGroups(messageLogsGroups [][]*types.MessageLog) ([]*types.Metric, error) {
 	var metrics []*types.Metric
 	for _, sortedMessageLogs := range messageLogsGroups {
-		metric, err := buildMetricsFromSortedMessageLogs(sortedMessageLogs)
+		// note: bound to this group's MessageID so every log line below can
+		// be grepped by message.
+		groupLog := logger.New("messageID", sortedMessageLogs[0].MessageID)
+
+		metric, err := buildMetricsFromSortedMessageLogs(groupLog, sortedMessageLogs)
 		if err != nil {
-			logger.Errorf("err building metrics:\n%v", err)
+			groupLog.Error("err building metrics", "err", err)
 			return nil, err
 		}
 
@@ -103,7 +106,7 @@ func buildMetricsFromSortedMessageLogsGroups(messageLogsGroups [][]*types.Messag
 	return metrics, nil
 }
 
-func buildMetricsFromSortedMessageLogs(sortedMessageLogs []*types.MessageLog) (*types.Metric, error) {
+func buildMetricsFromSortedMessageLogs(log logger.Logger, sortedMessageLogs []*types.MessageLog) (*types.Metric, error) {
 	var (
 		metric types.Metric
 		err    error
@@ -116,7 +119,7 @@ func buildMetricsFromSortedMessageLogs(sortedMessageLogs []*types.MessageLog) (*
 	metric.TotalNanoTime = calcTotalNanoTime(sortedMessageLogs)
 	metric.RelativeMessageRedundancy, err = calcRMR(sortedMessageLogs)
 	if err != nil {
-		logger.Errorf("err calculating rmr:\n%v", err)
+		log.Error("err calculating rmr", "err", err)
 		return nil, err
 	}
 	metric.LastDeliveryHop = calcLastDeliveryHop(sortedMessageLogs)
@@ -137,54 +140,20 @@ func calcRMR(sortedMessageLogs []*types.MessageLog) (float32, error) {
 	return (float32(len(sortedMessageLogs)) / (float32(uniqueHosts - 1))) - 1.0, nil
 }
 
+// calcLastDeliveryHop replays sortedMessageLogs through the same incremental
+// depth-map algorithm groupState.feed uses for the streaming path, instead
+// of its own recursive path-enumeration (the old buildPathsForSenderID DFS
+// had no base case for a leaf recipient, so it always returned zero paths
+// and this always returned 0). Batch and streaming now share one
+// implementation of "how many hops did this message's longest delivery
+// chain take".
 func calcLastDeliveryHop(sortedMessageLogs []*types.MessageLog) uint {
-	// note: map is senderID => recipientID
-	// note: assumes a host only ever sends a message to a recipient once!
-	m := make(map[string]map[string]*types.MessageLog)
+	g := newGroupState(sortedMessageLogs[0].MessageID)
 	for _, msg := range sortedMessageLogs {
-		if _, ok := m[msg.SenderID]; !ok {
-			m[msg.SenderID] = make(map[string]*types.MessageLog)
-		}
-
-		m[msg.SenderID][msg.HostID] = msg
-	}
-
-	// note: array length has already been checked, previously so this shouldn't panic... I hope :D
-	firstGossiperID := sortedMessageLogs[0].SenderID
-
-	// note: this assumes that a host never receives a message that it doesn't already have
-	paths := buildPathsForSenderID(firstGossiperID, m)
-
-	// find the longest path
-	lastDeliveryHop := 0.0
-	for _, path := range paths {
-		lastDeliveryHop = math.Max(lastDeliveryHop, float64(len(path)))
+		g.feed(msg)
 	}
 
-	return uint(lastDeliveryHop)
-}
-
-// note: map is senderID => recipientID
-// note: return is a chain of recipient IDs starting with the original senderID
-func buildPathsForSenderID(senderID string, m map[string]map[string]*types.MessageLog) [][]string {
-	var ret [][]string
-
-	for recipient := range m[senderID] {
-		paths := buildPathsForSenderID(recipient, m)
-		for _, path := range paths {
-			path = prependString(senderID, path)
-			ret = append(ret, path)
-		}
-	}
-
-	return ret
-}
-
-// TODO: is there a more efficient method?
-func prependString(s string, arr []string) []string {
-	tmp := []string{s}
-	tmp = append(tmp, arr...)
-	return tmp
+	return g.maxDepth
 }
 
 func countUniqueHosts(sortedMessageLogs []*types.MessageLog) uint {
@@ -227,19 +196,19 @@ func buildMessageLogFromStrings(data []string) (*types.MessageLog, error) {
 	// note: have already checked data length
 	seqNo, err := strconv.ParseUint(data[3], 10, 64)
 	if err != nil {
-		logger.Errorf("expected seqNo typeof Uint64 but got %s", data[3])
+		logger.Error("expected seqNo typeof Uint64", "got", data[3])
 		return nil, types.ErrImproperlyFormattedLogLine
 	}
 
 	unixNano, err := strconv.ParseInt(data[4], 10, 64)
 	if err != nil {
-		logger.Errorf("expected unixNano typeof Int64 but got %s", data[4])
+		logger.Error("expected unixNano typeof Int64", "got", data[4])
 		return nil, types.ErrImproperlyFormattedLogLine
 	}
 
 	seq, err := strconv.Atoi(data[5])
 	if err != nil {
-		logger.Errorf("expected seq typeof Int32 but got %s", data[5])
+		logger.Error("expected seq typeof Int32", "got", data[5])
 		return nil, types.ErrImproperlyFormattedLogLine
 	}
 
@@ -0,0 +1,20 @@
+package host
+
+import "errors"
+
+// note: errors for the get-latest repair protocol (see getlatest.go).
+var (
+	ErrFrameTooLarge      = errors.New("frame exceeds max frame size")
+	ErrGetLatestNoMessage = errors.New("no cached message for requested topic")
+	ErrGetLatestRemote    = errors.New("remote peer returned an error status")
+)
+
+// note: errors for the filter subscription protocol (see filter.go).
+var (
+	ErrUnknownFilterMsgType = errors.New("unknown filter message type")
+	ErrTooManyFilters       = errors.New("remote peer has reached its max filters")
+)
+
+// ErrImproperSecurityOption mirrors ErrImproperTransportOption: the 'none'
+// security option can't be combined with any other security option.
+var ErrImproperSecurityOption = errors.New("improper security option")
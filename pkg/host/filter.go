@@ -0,0 +1,560 @@
+package host
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agencyenterprise/gossip-host/internal/config"
+	"github.com/agencyenterprise/gossip-host/pkg/logger"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// FilterProtocolID is the stream protocol a "light" peer uses to register a
+// filter against a "full" peer and receive only matching gossip messages,
+// pushed over the same stream, instead of joining the full pubsub mesh for a
+// topic.
+const FilterProtocolID = "/gossip-host/filter/1.0.0"
+
+// DefaultMaxFiltersPerPeer bounds how many filters a single remote peer may
+// register, so a light peer can't exhaust a full peer's memory by
+// subscribing forever.
+const DefaultMaxFiltersPerPeer = 32
+
+type filterMsgType uint8
+
+const (
+	filterMsgSubscribe filterMsgType = iota
+	filterMsgUnsubscribe
+	filterMsgPing
+	filterMsgMatch
+)
+
+// FilterSpec describes what a light peer wants to receive for a topic: a
+// message matches if it's on Topic and satisfies every non-empty predicate
+// below. It's also the shape used for operator-declared default filters in
+// config (Host.Filters).
+type FilterSpec struct {
+	Topic            string
+	MessageIDPrefix  string
+	SenderAllowlist  []string
+	PayloadSubstring string
+}
+
+// matches reports whether msg, delivered by senderID with the given
+// messageID, satisfies every predicate set on spec.
+func (spec FilterSpec) matches(senderID, messageID string, payload []byte) bool {
+	if spec.MessageIDPrefix != "" && !strings.HasPrefix(messageID, spec.MessageIDPrefix) {
+		return false
+	}
+
+	if len(spec.SenderAllowlist) > 0 {
+		allowed := false
+		for _, s := range spec.SenderAllowlist {
+			if s == senderID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if spec.PayloadSubstring != "" && !strings.Contains(string(payload), spec.PayloadSubstring) {
+		return false
+	}
+
+	return true
+}
+
+func (spec *FilterSpec) marshalTo(w io.Writer) error {
+	if err := writeString(w, spec.Topic); err != nil {
+		return err
+	}
+	if err := writeString(w, spec.MessageIDPrefix); err != nil {
+		return err
+	}
+	if err := writeUint32(w, uint32(len(spec.SenderAllowlist))); err != nil {
+		return err
+	}
+	for _, s := range spec.SenderAllowlist {
+		if err := writeString(w, s); err != nil {
+			return err
+		}
+	}
+	return writeString(w, spec.PayloadSubstring)
+}
+
+func readFilterSpec(r io.Reader) (*FilterSpec, error) {
+	topic, err := readString(r, maxFrameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := readString(r, maxFrameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxFilterAllowlistLen {
+		return nil, ErrFrameTooLarge
+	}
+
+	allowlist := make([]string, n)
+	for i := range allowlist {
+		allowlist[i], err = readString(r, maxFrameSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	substr, err := readString(r, maxFrameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilterSpec{
+		Topic:            topic,
+		MessageIDPrefix:  prefix,
+		SenderAllowlist:  allowlist,
+		PayloadSubstring: substr,
+	}, nil
+}
+
+// maxFilterAllowlistLen bounds how many sender IDs a single FilterSpec may
+// carry over the wire.
+const maxFilterAllowlistLen = 1 << 16
+
+// filterMsg is the single message type exchanged on the filter stream;
+// Type selects which fields are meaningful.
+type filterMsg struct {
+	Type      filterMsgType
+	Spec      *FilterSpec // subscribe
+	Topic     string      // unsubscribe, match
+	Payload   []byte      // match
+	SenderID  string      // match
+	MessageID string      // match
+}
+
+func (m *filterMsg) marshalTo(w io.Writer) error {
+	if err := writeUint32(w, uint32(m.Type)); err != nil {
+		return err
+	}
+
+	switch m.Type {
+	case filterMsgSubscribe:
+		return m.Spec.marshalTo(w)
+
+	case filterMsgUnsubscribe:
+		return writeString(w, m.Topic)
+
+	case filterMsgPing:
+		return nil
+
+	case filterMsgMatch:
+		if err := writeString(w, m.Topic); err != nil {
+			return err
+		}
+		if err := writeString(w, m.SenderID); err != nil {
+			return err
+		}
+		if err := writeString(w, m.MessageID); err != nil {
+			return err
+		}
+		return writeBytes(w, m.Payload)
+	}
+
+	return nil
+}
+
+func readFilterMsg(r io.Reader) (*filterMsg, error) {
+	t, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &filterMsg{Type: filterMsgType(t)}
+
+	switch msg.Type {
+	case filterMsgSubscribe:
+		msg.Spec, err = readFilterSpec(r)
+		if err != nil {
+			return nil, err
+		}
+
+	case filterMsgUnsubscribe:
+		msg.Topic, err = readString(r, maxFrameSize)
+		if err != nil {
+			return nil, err
+		}
+
+	case filterMsgPing:
+		// no payload
+
+	case filterMsgMatch:
+		if msg.Topic, err = readString(r, maxFrameSize); err != nil {
+			return nil, err
+		}
+		if msg.SenderID, err = readString(r, maxFrameSize); err != nil {
+			return nil, err
+		}
+		if msg.MessageID, err = readString(r, maxFrameSize); err != nil {
+			return nil, err
+		}
+		if msg.Payload, err = readBytes(r, maxFrameSize); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, ErrUnknownFilterMsgType
+	}
+
+	return msg, nil
+}
+
+// filterWriteTimeout bounds how long a single match write to a remote
+// peer's filter stream may block, so a stalled light peer can't wedge
+// whatever goroutine is calling dispatch.
+const filterWriteTimeout = 5 * time.Second
+
+// remoteFilters is the set of filters a single remote peer has registered,
+// capped at maxFilters.
+type remoteFilters struct {
+	mu         sync.RWMutex
+	specs      []FilterSpec
+	maxFilters int
+	stream     network.Stream
+
+	// writeMu serializes writes to stream: specs/maxFilters above guard the
+	// filter list, but the stream itself needs its own lock so two matches
+	// dispatched concurrently don't interleave their frames.
+	writeMu sync.Mutex
+}
+
+// writeMatch sends a single filterMsgMatch to rf's remote peer, holding
+// writeMu for the duration so concurrent dispatch calls can't interleave
+// frames on the stream.
+func (rf *remoteFilters) writeMatch(topic, senderID, messageID string, payload []byte) error {
+	rf.writeMu.Lock()
+	defer rf.writeMu.Unlock()
+
+	if err := rf.stream.SetWriteDeadline(time.Now().Add(filterWriteTimeout)); err != nil {
+		return err
+	}
+	defer rf.stream.SetWriteDeadline(time.Time{})
+
+	match := &filterMsg{Type: filterMsgMatch, Topic: topic, SenderID: senderID, MessageID: messageID, Payload: payload}
+	return match.marshalTo(rf.stream)
+}
+
+// filterTable tracks, per remote peer, which filters it has registered
+// against this (full) node.
+type filterTable struct {
+	mu         sync.RWMutex
+	byPeer     map[peer.ID]*remoteFilters
+	maxFilters int
+}
+
+func newFilterTable(maxFiltersPerPeer int) *filterTable {
+	if maxFiltersPerPeer <= 0 {
+		maxFiltersPerPeer = DefaultMaxFiltersPerPeer
+	}
+
+	return &filterTable{
+		byPeer:     make(map[peer.ID]*remoteFilters),
+		maxFilters: maxFiltersPerPeer,
+	}
+}
+
+func (t *filterTable) subscribe(p peer.ID, s network.Stream, spec FilterSpec) error {
+	t.mu.Lock()
+	rf, ok := t.byPeer[p]
+	if !ok {
+		rf = &remoteFilters{maxFilters: t.maxFilters, stream: s}
+		t.byPeer[p] = rf
+	}
+	t.mu.Unlock()
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if len(rf.specs) >= rf.maxFilters {
+		return ErrTooManyFilters
+	}
+
+	rf.specs = append(rf.specs, spec)
+	return nil
+}
+
+func (t *filterTable) unsubscribe(p peer.ID, topic string) {
+	t.mu.RLock()
+	rf, ok := t.byPeer[p]
+	t.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	kept := rf.specs[:0]
+	for _, spec := range rf.specs {
+		if spec.Topic != topic {
+			kept = append(kept, spec)
+		}
+	}
+	rf.specs = kept
+}
+
+func (t *filterTable) remove(p peer.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.byPeer, p)
+}
+
+// dispatch forwards (senderID, messageID, payload) on topic to every remote
+// peer whose registered filters match it. Matching runs under t's read lock,
+// but the (potentially slow) writes themselves happen after it's released and
+// concurrently with each other, so one stalled subscriber can't hold up
+// delivery to the rest or block a concurrent subscribe/unsubscribe.
+func (t *filterTable) dispatch(topic, senderID, messageID string, payload []byte) {
+	type match struct {
+		p  peer.ID
+		rf *remoteFilters
+	}
+
+	t.mu.RLock()
+	var matches []match
+	for p, rf := range t.byPeer {
+		rf.mu.RLock()
+		matched := false
+		for _, spec := range rf.specs {
+			if spec.Topic == topic && spec.matches(senderID, messageID, payload) {
+				matched = true
+				break
+			}
+		}
+		rf.mu.RUnlock()
+
+		if matched {
+			matches = append(matches, match{p: p, rf: rf})
+		}
+	}
+	t.mu.RUnlock()
+
+	for _, m := range matches {
+		go func(p peer.ID, rf *remoteFilters) {
+			if err := rf.writeMatch(topic, senderID, messageID, payload); err != nil {
+				logger.Error("err forwarding filtered message", "peer", p, "err", err)
+			}
+		}(m.p, m.rf)
+	}
+}
+
+// filterSpecFromConfig converts an operator-declared default filter from
+// config into the FilterSpec the host package works with.
+func filterSpecFromConfig(c config.FilterSpec) FilterSpec {
+	return FilterSpec{
+		Topic:            c.Topic,
+		MessageIDPrefix:  c.MessageIDPrefix,
+		SenderAllowlist:  c.SenderAllowlist,
+		PayloadSubstring: c.PayloadSubstring,
+	}
+}
+
+// registerFilterHandler wires the filter protocol into h: each stream is a
+// long-lived connection from a light peer that subscribes/unsubscribes and
+// receives matches until it closes the stream.
+func registerFilterHandler(h host.Host, table *filterTable) {
+	h.SetStreamHandler(FilterProtocolID, newFilterStreamHandler(table))
+}
+
+func newFilterStreamHandler(table *filterTable) network.StreamHandler {
+	return func(s network.Stream) {
+		p := s.Conn().RemotePeer()
+		defer func() {
+			table.remove(p)
+			s.Close()
+		}()
+
+		for {
+			msg, err := readFilterMsg(s)
+			if err != nil {
+				if err != io.EOF {
+					logger.Error("err reading filter message", "peer", p, "err", err)
+				}
+				return
+			}
+
+			switch msg.Type {
+			case filterMsgSubscribe:
+				if err := table.subscribe(p, s, *msg.Spec); err != nil {
+					logger.Error("err subscribing filter", "peer", p, "err", err)
+					return
+				}
+
+			case filterMsgUnsubscribe:
+				table.unsubscribe(p, msg.Topic)
+
+			case filterMsgPing:
+				// no-op; just keeps the stream alive and proves it's usable
+
+			default:
+				logger.Error("unexpected filter message type", "type", msg.Type, "peer", p)
+				return
+			}
+		}
+	}
+}
+
+// subscribeFilter opens a filter stream to a full peer and registers spec
+// against it, returning the open stream so the caller can read pushed
+// matches and later unsubscribe/close.
+func subscribeFilter(ctx context.Context, h host.Host, p peer.ID, spec FilterSpec) (network.Stream, error) {
+	s, err := h.NewStream(ctx, p, FilterProtocolID)
+	if err != nil {
+		logger.Error("err opening filter stream", "peer", p, "err", err)
+		return nil, err
+	}
+
+	msg := &filterMsg{Type: filterMsgSubscribe, Spec: &spec}
+	if err := msg.marshalTo(s); err != nil {
+		logger.Error("err sending filter subscription", "peer", p, "err", err)
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// FilterService is the package's entry point for the filter-subscription
+// protocol: as a full node it answers subscribe/unsubscribe from light
+// peers and pushes them matches (Dispatch), and as a light node it opens
+// filter subscriptions against full peers using the operator-declared
+// defaults from config (SubscribeDefaults) and hands whatever comes back to
+// onMatch.
+type FilterService struct {
+	h     host.Host
+	table *filterTable
+
+	onMatch func(topic, senderID, messageID string, payload []byte)
+
+	// subMu guards subs, this node's own outbound subscriptions (as a light
+	// node), keyed by topic, so Unsubscribe can find and close them.
+	subMu sync.Mutex
+	subs  map[string]network.Stream
+}
+
+// NewFilterService builds a FilterService bound to h and immediately
+// registers the filter stream handler, so h starts accepting
+// subscribe/unsubscribe from light peers right away. maxFiltersPerPeer <= 0
+// uses DefaultMaxFiltersPerPeer. onMatch, if non-nil, is called for every
+// match pushed back on a subscription this node opened via SubscribeDefaults.
+func NewFilterService(h host.Host, maxFiltersPerPeer int, onMatch func(topic, senderID, messageID string, payload []byte)) *FilterService {
+	table := newFilterTable(maxFiltersPerPeer)
+	registerFilterHandler(h, table)
+
+	return &FilterService{
+		h:       h,
+		table:   table,
+		onMatch: onMatch,
+		subs:    make(map[string]network.Stream),
+	}
+}
+
+// Dispatch forwards one delivered gossip message through the filter table,
+// pushing it to every remote peer whose registered filter matches. Call it
+// from the gossip message handler for every message this (full) node
+// receives, so filter-subscribed light peers actually get their matches.
+func (fs *FilterService) Dispatch(topic, senderID, messageID string, payload []byte) {
+	fs.table.dispatch(topic, senderID, messageID, payload)
+}
+
+// SubscribeDefaults opens a filter subscription to p for every
+// operator-declared default filter (config.Host.Filters), so a light node
+// automatically filter-subscribes to a full peer with the configured
+// defaults instead of joining full gossip for those topics. Each
+// subscription's pushed matches are drained by readMatches in their own
+// goroutine and handed to onMatch.
+func (fs *FilterService) SubscribeDefaults(ctx context.Context, p peer.ID, defaults []config.FilterSpec) error {
+	for _, c := range defaults {
+		spec := filterSpecFromConfig(c)
+
+		s, err := subscribeFilter(ctx, fs.h, p, spec)
+		if err != nil {
+			return err
+		}
+
+		fs.subMu.Lock()
+		fs.subs[spec.Topic] = s
+		fs.subMu.Unlock()
+
+		go fs.readMatches(spec.Topic, s)
+	}
+
+	return nil
+}
+
+// readMatches drains filterMsgMatch frames pushed on s, handing each to
+// fs.onMatch, until s errors or is closed - this is the read side of the
+// push-style delivery subscribeFilter sets up, without which a full peer's
+// dispatch would eventually block every writer on an unread stream. It
+// closes s and forgets it from fs.subs when it returns.
+func (fs *FilterService) readMatches(topic string, s network.Stream) {
+	defer func() {
+		fs.subMu.Lock()
+		if fs.subs[topic] == s {
+			delete(fs.subs, topic)
+		}
+		fs.subMu.Unlock()
+
+		s.Close()
+	}()
+
+	for {
+		msg, err := readFilterMsg(s)
+		if err != nil {
+			if err != io.EOF {
+				logger.Error("err reading filter match", "peer", s.Conn().RemotePeer(), "err", err)
+			}
+			return
+		}
+
+		if msg.Type != filterMsgMatch {
+			logger.Error("unexpected filter message type from full peer", "type", msg.Type, "peer", s.Conn().RemotePeer())
+			continue
+		}
+
+		if fs.onMatch != nil {
+			fs.onMatch(msg.Topic, msg.SenderID, msg.MessageID, msg.Payload)
+		}
+	}
+}
+
+// Unsubscribe sends filterMsgUnsubscribe for topic on this node's own
+// subscription (opened via SubscribeDefaults) and closes it; readMatches'
+// next read then fails and finishes cleaning it up. It's a no-op if topic
+// was never subscribed.
+func (fs *FilterService) Unsubscribe(topic string) error {
+	fs.subMu.Lock()
+	s, ok := fs.subs[topic]
+	fs.subMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	msg := &filterMsg{Type: filterMsgUnsubscribe, Topic: topic}
+	err := msg.marshalTo(s)
+	s.Close()
+	return err
+}
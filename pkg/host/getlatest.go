@@ -0,0 +1,385 @@
+package host
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/agencyenterprise/gossip-host/pkg/logger"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// GetLatestProtocolID is the stream protocol used to ask a peer for the
+// latest message it has seen on a topic. It exists to repair gaps left by
+// pubsub floods: rather than waiting for a retransmit that may never come, a
+// peer that notices it's missing messages (stale topic, a SeqNo gap) can pull
+// the latest one directly. Modeled after libp2p-pubsub-router's
+// getLatestProtocol.
+const GetLatestProtocolID = "/gossip-host/get-latest/1.0.0"
+
+type getLatestStatus int32
+
+const (
+	getLatestStatusOK getLatestStatus = iota
+	getLatestStatusErr
+	getLatestStatusNoMessage
+)
+
+type getLatestRequest struct {
+	Topic         string
+	SinceSeqNo    uint64
+	SinceNanoTime int64
+}
+
+func (req *getLatestRequest) marshalTo(w io.Writer) error {
+	if err := writeString(w, req.Topic); err != nil {
+		return err
+	}
+	if err := writeUint64(w, req.SinceSeqNo); err != nil {
+		return err
+	}
+	return writeInt64(w, req.SinceNanoTime)
+}
+
+func readGetLatestRequest(r io.Reader) (*getLatestRequest, error) {
+	topic, err := readString(r, maxFrameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sinceSeqNo, err := readUint64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sinceNanoTime, err := readInt64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getLatestRequest{
+		Topic:         topic,
+		SinceSeqNo:    sinceSeqNo,
+		SinceNanoTime: sinceNanoTime,
+	}, nil
+}
+
+type getLatestResponse struct {
+	Status   getLatestStatus
+	Payload  []byte
+	SeqNo    uint64
+	NanoTime int64
+}
+
+func (resp *getLatestResponse) marshalTo(w io.Writer) error {
+	if err := writeUint32(w, uint32(resp.Status)); err != nil {
+		return err
+	}
+	if err := writeBytes(w, resp.Payload); err != nil {
+		return err
+	}
+	if err := writeUint64(w, resp.SeqNo); err != nil {
+		return err
+	}
+	return writeInt64(w, resp.NanoTime)
+}
+
+func readGetLatestResponse(r io.Reader) (*getLatestResponse, error) {
+	status, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := readBytes(r, maxFrameSize)
+	if err != nil {
+		return nil, err
+	}
+
+	seqNo, err := readUint64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	nanoTime, err := readInt64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &getLatestResponse{
+		Status:   getLatestStatus(status),
+		Payload:  payload,
+		SeqNo:    seqNo,
+		NanoTime: nanoTime,
+	}, nil
+}
+
+// cachedMessage is the last message seen for a topic, kept around so this
+// host can answer get-latest requests from peers.
+type cachedMessage struct {
+	Payload  []byte
+	SeqNo    uint64
+	NanoTime int64
+}
+
+// latestMessageCache tracks the most recent message per topic. It's fed by
+// Repair.Observe (see below), which is the hook the gossip message handler
+// is meant to call for every delivery, and read by the get-latest stream
+// handler.
+type latestMessageCache struct {
+	mu      sync.RWMutex
+	byTopic map[string]cachedMessage
+}
+
+func newLatestMessageCache() *latestMessageCache {
+	return &latestMessageCache{
+		byTopic: make(map[string]cachedMessage),
+	}
+}
+
+func (c *latestMessageCache) put(topic string, msg cachedMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byTopic[topic] = msg
+}
+
+func (c *latestMessageCache) get(topic string) (cachedMessage, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	msg, ok := c.byTopic[topic]
+	return msg, ok
+}
+
+// registerGetLatestHandler wires the get-latest protocol into h, answering
+// requests from the given cache.
+func registerGetLatestHandler(h host.Host, cache *latestMessageCache) {
+	h.SetStreamHandler(GetLatestProtocolID, newGetLatestStreamHandler(cache))
+}
+
+func newGetLatestStreamHandler(cache *latestMessageCache) network.StreamHandler {
+	return func(s network.Stream) {
+		defer s.Close()
+
+		req, err := readGetLatestRequest(s)
+		if err != nil {
+			logger.Error("err reading get-latest request", "peer", s.Conn().RemotePeer(), "err", err)
+			return
+		}
+
+		resp := &getLatestResponse{Status: getLatestStatusNoMessage}
+		if msg, ok := cache.get(req.Topic); ok {
+			resp = &getLatestResponse{
+				Status:   getLatestStatusOK,
+				Payload:  msg.Payload,
+				SeqNo:    msg.SeqNo,
+				NanoTime: msg.NanoTime,
+			}
+		}
+
+		if err := resp.marshalTo(s); err != nil {
+			logger.Error("err writing get-latest response", "peer", s.Conn().RemotePeer(), "err", err)
+		}
+	}
+}
+
+// requestLatest opens a get-latest stream to p and asks for the latest
+// message on topic. sinceSeqNo/sinceNanoTime are informational hints for the
+// responder (e.g. to skip the reply if it wouldn't be newer); this host
+// still treats any OK response as the latest known message.
+func requestLatest(ctx context.Context, h host.Host, p peer.ID, topic string, sinceSeqNo uint64, sinceNanoTime int64) (*getLatestResponse, error) {
+	s, err := h.NewStream(ctx, p, GetLatestProtocolID)
+	if err != nil {
+		logger.Error("err opening get-latest stream", "peer", p, "err", err)
+		return nil, err
+	}
+	defer s.Close()
+
+	req := &getLatestRequest{Topic: topic, SinceSeqNo: sinceSeqNo, SinceNanoTime: sinceNanoTime}
+	if err := req.marshalTo(s); err != nil {
+		logger.Error("err writing get-latest request", "peer", p, "err", err)
+		return nil, err
+	}
+
+	resp, err := readGetLatestResponse(s)
+	if err != nil {
+		logger.Error("err reading get-latest response", "peer", p, "err", err)
+		return nil, err
+	}
+
+	switch resp.Status {
+	case getLatestStatusOK:
+		return resp, nil
+
+	case getLatestStatusNoMessage:
+		return nil, ErrGetLatestNoMessage
+
+	default:
+		return nil, ErrGetLatestRemote
+	}
+}
+
+// DefaultStaleAfter is how long a topic can go without a new observed
+// message before Repair treats it as stale and pulls a repair from a
+// connected peer.
+const DefaultStaleAfter = 30 * time.Second
+
+// DefaultStaleCheckInterval is how often Repair's staleness watcher scans
+// for topics that have gone the Repair's staleAfter quiet.
+const DefaultStaleCheckInterval = 5 * time.Second
+
+// Repair is the get-latest protocol's self-healing entry point for a host:
+// it answers other peers' get-latest requests (via the cache Observe
+// feeds), and watches its own topics for the two triggers the request asks
+// for - a quiet topic, or a SeqNo gap - so it can pull a repair itself.
+//
+// The gossip message handler (outside this package) is expected to call
+// Observe for every message it delivers; onRepaired is called with whatever
+// Repair recovers, so a caller can feed it back into the same delivery path
+// instead of it only ever being logged.
+type Repair struct {
+	h host.Host
+
+	cache      *latestMessageCache
+	staleAfter time.Duration
+	onRepaired func(topic string, payload []byte, seqNo uint64, nanoTime int64)
+
+	mu        sync.Mutex
+	lastSeen  map[string]time.Time
+	lastSeqNo map[string]uint64
+}
+
+// NewRepair builds a Repair bound to h and immediately registers the
+// get-latest stream handler, so h starts answering other peers' get-latest
+// requests right away. staleAfter <= 0 uses DefaultStaleAfter.
+func NewRepair(h host.Host, staleAfter time.Duration, onRepaired func(topic string, payload []byte, seqNo uint64, nanoTime int64)) *Repair {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+
+	r := &Repair{
+		h:          h,
+		cache:      newLatestMessageCache(),
+		staleAfter: staleAfter,
+		onRepaired: onRepaired,
+		lastSeen:   make(map[string]time.Time),
+		lastSeqNo:  make(map[string]uint64),
+	}
+
+	registerGetLatestHandler(h, r.cache)
+
+	return r
+}
+
+// Observe feeds a message delivered on topic into the cache this host
+// answers get-latest requests from, and checks seqNo against the last one
+// seen on topic for a gap implying this host already missed a message.
+func (r *Repair) Observe(topic string, payload []byte, seqNo uint64, nanoTime int64) {
+	r.cache.put(topic, cachedMessage{Payload: payload, SeqNo: seqNo, NanoTime: nanoTime})
+
+	r.mu.Lock()
+	lastSeqNo, hadPrev := r.lastSeqNo[topic]
+	gap := hadPrev && seqNo > lastSeqNo+1
+	r.lastSeqNo[topic] = seqNo
+	r.lastSeen[topic] = time.Now()
+	r.mu.Unlock()
+
+	if gap {
+		logger.Warn("seqno gap detected, requesting repair", "topic", topic, "lastSeqNo", lastSeqNo, "seqNo", seqNo)
+		go r.repair(topic, lastSeqNo, nanoTime)
+	}
+}
+
+// WatchStaleness blocks, periodically scanning every topic Observe has seen
+// for one that's gone r.staleAfter without a new message and requesting a
+// repair for it. It returns when ctx is canceled; callers should run it in
+// its own goroutine.
+func (r *Repair) WatchStaleness(ctx context.Context) {
+	ticker := time.NewTicker(DefaultStaleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			r.checkStaleness()
+		}
+	}
+}
+
+func (r *Repair) checkStaleness() {
+	now := time.Now()
+
+	r.mu.Lock()
+	staleSeqNo := make(map[string]uint64)
+	for topic, lastSeen := range r.lastSeen {
+		if now.Sub(lastSeen) >= r.staleAfter {
+			staleSeqNo[topic] = r.lastSeqNo[topic]
+		}
+	}
+	r.mu.Unlock()
+
+	for topic, sinceSeqNo := range staleSeqNo {
+		logger.Warn("topic stale, requesting repair", "topic", topic, "staleAfter", r.staleAfter)
+		go r.repair(topic, sinceSeqNo, 0)
+	}
+}
+
+// repair queries every currently connected peer for topic's latest message,
+// stopping at the first usable response: it's cached (so this host can
+// answer get-latest requests for topic too) and handed to onRepaired.
+func (r *Repair) repair(topic string, sinceSeqNo uint64, sinceNanoTime int64) {
+	for _, p := range r.h.Network().Peers() {
+		resp, err := requestLatest(context.Background(), r.h, p, topic, sinceSeqNo, sinceNanoTime)
+		if err != nil {
+			continue
+		}
+
+		r.mu.Lock()
+		r.lastSeqNo[topic] = resp.SeqNo
+		r.lastSeen[topic] = time.Now()
+		r.mu.Unlock()
+
+		r.cache.put(topic, cachedMessage{Payload: resp.Payload, SeqNo: resp.SeqNo, NanoTime: resp.NanoTime})
+
+		if r.onRepaired != nil {
+			r.onRepaired(topic, resp.Payload, resp.SeqNo, resp.NanoTime)
+		}
+
+		return
+	}
+
+	logger.Error("err repairing topic: no connected peer had a usable response", "topic", topic)
+}
+
+// bootstrapRepair queries p for the latest message on each of topics and
+// feeds whatever comes back through r, same as an organic stale/gap repair.
+// It's called once per bootstrap peer from connectToPeers so a freshly
+// connected host repairs any gaps left by gossip floods it missed before it
+// joined the mesh.
+func bootstrapRepair(ctx context.Context, r *Repair, p peer.ID, topics []string) {
+	for _, topic := range topics {
+		resp, err := requestLatest(ctx, r.h, p, topic, 0, 0)
+		if err != nil {
+			if err != ErrGetLatestNoMessage && err != ErrGetLatestRemote {
+				logger.Error("err requesting latest message", "topic", topic, "peer", p, "err", err)
+			}
+			continue
+		}
+
+		r.mu.Lock()
+		r.lastSeqNo[topic] = resp.SeqNo
+		r.lastSeen[topic] = time.Now()
+		r.mu.Unlock()
+
+		r.cache.put(topic, cachedMessage{Payload: resp.Payload, SeqNo: resp.SeqNo, NanoTime: resp.NanoTime})
+
+		if r.onRepaired != nil {
+			r.onRepaired(topic, resp.Payload, resp.SeqNo, resp.NanoTime)
+		}
+	}
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"strings"
 
+	"github.com/agencyenterprise/gossip-host/internal/config"
 	"github.com/agencyenterprise/gossip-host/pkg/logger"
 
 	ipfsaddr "github.com/ipfs/go-ipfs-addr"
 	libp2p "github.com/libp2p/go-libp2p"
+	lcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
 	mplex "github.com/libp2p/go-libp2p-mplex"
+	noise "github.com/libp2p/go-libp2p-noise"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	quic "github.com/libp2p/go-libp2p-quic-transport"
 	secio "github.com/libp2p/go-libp2p-secio"
+	ltls "github.com/libp2p/go-libp2p-tls"
 	yamux "github.com/libp2p/go-libp2p-yamux"
 	lconfig "github.com/libp2p/go-libp2p/config"
 	tcp "github.com/libp2p/go-tcp-transport"
@@ -60,7 +65,7 @@ func parseTransportOptions(opts []string) (lconfig.Option, error) {
 			lOpts = append(lOpts, libp2p.DefaultTransports)
 
 		default:
-			logger.Errorf("unknown transport option: %s", opt)
+			logger.Error("unknown transport option", "opt", opt)
 			return nil, ErrUnknownTransportOption
 		}
 	}
@@ -73,7 +78,7 @@ func parseMuxerOptions(opts [][]string) (lconfig.Option, error) {
 
 	for _, opt := range opts {
 		if len(opt) != 2 {
-			logger.Errorf("improper muxer format, expected ['name', 'type'], received %v", opt)
+			logger.Error("improper muxer format, expected ['name', 'type']", "opt", opt)
 			return nil, ErrImproperMuxerOption
 		}
 
@@ -86,7 +91,7 @@ func parseMuxerOptions(opts [][]string) (lconfig.Option, error) {
 
 		// TODO: add others?
 		default:
-			logger.Errorf("unknown muxer option: %s", opt)
+			logger.Error("unknown muxer option", "opt", opt)
 			return nil, ErrUnknownMuxerOption
 		}
 	}
@@ -94,49 +99,104 @@ func parseMuxerOptions(opts [][]string) (lconfig.Option, error) {
 	return libp2p.ChainOptions(lOpts...), nil
 }
 
-func parseSecurityOptions(opt string) (lconfig.Option, error) {
-	switch strings.ToLower(opt) {
-	case "secio":
-		return libp2p.Security(secio.ID, secio.New), nil
+// parseSecurityOptions builds the security transport chain from opts, in
+// the order given, mirroring how parseTransportOptions lets operators
+// declare a preference order. TLS and Noise both need the host's private
+// key to build their transport, so it's passed in explicitly rather than
+// pulled from config here.
+func parseSecurityOptions(opts []string, priv lcrypto.PrivKey) (lconfig.Option, error) {
+	var lOpts []lconfig.Option
+
+	for _, opt := range opts {
+		switch strings.ToLower(opt) {
+		case "secio":
+			// note: secio is deprecated upstream; keep accepting it so
+			// existing configs still load, but steer operators to tls/noise.
+			logger.Warn("security option 'secio' is deprecated, prefer 'tls' or 'noise'", "opt", opt)
+			lOpts = append(lOpts, libp2p.Security(secio.ID, secio.New))
+
+		case "tls":
+			lOpts = append(lOpts, libp2p.Security(ltls.ID, func() (*ltls.Transport, error) {
+				return ltls.New(priv)
+			}))
 
-	case "default":
-		return libp2p.Security(secio.ID, secio.New), nil
+		case "noise":
+			lOpts = append(lOpts, libp2p.Security(noise.ID, func() (*noise.Transport, error) {
+				return noise.New(priv)
+			}))
+
+		case "default":
+			lOpts = append(lOpts, libp2p.Security(ltls.ID, func() (*ltls.Transport, error) {
+				return ltls.New(priv)
+			}))
+
+		case "none":
+			if len(opts) > 1 {
+				logger.Error("when using the 'none' security option, cannot also specify other security options")
+				return nil, ErrImproperSecurityOption
+			}
 
-	// TODO: add others?
-	case "none":
-		return libp2p.NoSecurity, nil
+			return libp2p.NoSecurity, nil
 
-	default:
-		logger.Errorf("unknown security option: %s", opt)
-		return nil, ErrUnknownSecurityOption
+		default:
+			logger.Error("unknown security option", "opt", opt)
+			return nil, ErrUnknownSecurityOption
+		}
 	}
+
+	return libp2p.ChainOptions(lOpts...), nil
 }
 
 // note: it expects the peers to be in IPFS form
-func connectToPeers(ctx context.Context, host host.Host, peers []string) error {
+//
+// repair and topics, when both non-nil/non-empty, are used to query each
+// bootstrapping peer via the get-latest protocol once connected, so this
+// host can repair any gossip it missed before it joined the mesh.
+//
+// filterService and filterDefaults, when both non-nil/non-empty, open a
+// filter subscription against each bootstrapping peer for every
+// operator-declared default filter, so a light node doesn't need to join
+// full gossip to get the topics it cares about.
+func connectToPeers(ctx context.Context, h host.Host, peers []string, repair *Repair, topics []string, filterService *FilterService, filterDefaults []config.FilterSpec) error {
 	for _, p := range peers {
 		addr, err := ipfsaddr.ParseString(p)
 		if err != nil {
-			logger.Errorf("err parsing peer: %s\n%v", p, err)
+			logger.Error("err parsing peer", "peer", p, "err", err)
 			return err
 		}
 
 		pinfo, err := peerstore.InfoFromP2pAddr(addr.Multiaddr())
 		if err != nil {
-			logger.Errorf("err getting info from peerstore\n%v", err)
+			logger.Error("err getting info from peerstore", "peer", p, "err", err)
 			return err
 		}
 
-		logger.Infof("full peer addr: %s", addr.String())
-		logger.Infof("peer info: %v", pinfo)
+		// note: bound to this peer so the three lines below (and anything the
+		// get-latest repair query logs) can be grepped by peer.
+		peerLog := logger.New("peer", pinfo.ID)
+
+		peerLog.Info("full peer addr", "addr", addr.String())
+		peerLog.Info("peer info", "pinfo", pinfo)
 
-		if err := host.Connect(ctx, *pinfo); err != nil {
-			logger.Errorf("bootstrapping a peer failed\n%v", err)
+		if err := h.Connect(ctx, *pinfo); err != nil {
+			peerLog.Error("bootstrapping a peer failed", "err", err)
 			return err
 		}
 
-		logger.Infof("Connected to peer: %v", pinfo.ID)
+		peerLog.Info("connected to peer")
+
+		if repair != nil && len(topics) > 0 {
+			go bootstrapRepair(ctx, repair, pinfo.ID, topics)
+		}
+
+		if filterService != nil && len(filterDefaults) > 0 {
+			go func(p peer.ID) {
+				if err := filterService.SubscribeDefaults(ctx, p, filterDefaults); err != nil {
+					peerLog.Error("err subscribing default filters", "err", err)
+				}
+			}(pinfo.ID)
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,97 @@
+package host
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// note: these helpers implement a small length-prefixed wire format shared by
+// the host package's request/response stream protocols (get-latest, filter).
+// Each message's fields are written in the fixed order its marshalTo method
+// uses and read back in that same order by its read function; there is no
+// schema negotiation, so request/response shapes must stay in lockstep
+// between peers running the same protocol version.
+
+func writeUint64(w io.Writer, v uint64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	_, err := w.Write(buf[:])
+	return err
+}
+
+func readUint64(r io.Reader) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func writeInt64(w io.Writer, v int64) error {
+	return writeUint64(w, uint64(v))
+}
+
+func readInt64(r io.Reader) (int64, error) {
+	v, err := readUint64(r)
+	return int64(v), err
+}
+
+func writeUint32(w io.Writer, v uint32) error {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	_, err := w.Write(buf[:])
+	return err
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(buf[:]), nil
+}
+
+// writeBytes writes a uint32 length prefix followed by the raw bytes.
+func writeBytes(w io.Writer, b []byte) error {
+	if err := writeUint32(w, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// readBytes reads a uint32 length prefix followed by that many raw bytes.
+//
+// note: maxFrameSize guards against a misbehaving or malicious peer claiming
+// an enormous length and forcing a huge allocation.
+func readBytes(r io.Reader, maxFrameSize uint32) ([]byte, error) {
+	n, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func writeString(w io.Writer, s string) error {
+	return writeBytes(w, []byte(s))
+}
+
+func readString(r io.Reader, maxFrameSize uint32) (string, error) {
+	b, err := readBytes(r, maxFrameSize)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// maxFrameSize bounds any single length-prefixed field read from a stream
+// protocol in this package.
+const maxFrameSize = 1 << 20 // 1MB
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Handler processes a Record, e.g. by formatting and writing it somewhere.
+type Handler interface {
+	Log(r *Record) error
+}
+
+// HandlerFunc is a Handler implemented as a plain function.
+type HandlerFunc func(r *Record) error
+
+func (f HandlerFunc) Log(r *Record) error { return f(r) }
+
+// syncHandler serializes access to an underlying Handler so concurrent
+// loggers sharing it (e.g. every child of the root logger) don't interleave
+// writes.
+type syncHandler struct {
+	mu sync.Mutex
+	h  Handler
+}
+
+func syncWrap(h Handler) Handler {
+	return &syncHandler{h: h}
+}
+
+func (s *syncHandler) Log(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.h.Log(r)
+}
+
+// LvlFilterHandler drops any record more verbose than maxLvl before handing
+// it to h.
+func LvlFilterHandler(maxLvl Lvl, h Handler) Handler {
+	return HandlerFunc(func(r *Record) error {
+		if r.Lvl > maxLvl {
+			return nil
+		}
+		return h.Log(r)
+	})
+}
+
+// JSONHandler writes each record as a single line of JSON to w.
+func JSONHandler(w io.Writer) Handler {
+	return syncWrap(HandlerFunc(func(r *Record) error {
+		fields := map[string]interface{}{
+			"t":   r.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+			"lvl": r.Lvl.String(),
+			"msg": r.Msg,
+		}
+
+		for i := 0; i+1 < len(r.Ctx); i += 2 {
+			key := fmt.Sprintf("%v", r.Ctx[i])
+			fields[key] = r.Ctx[i+1]
+		}
+
+		enc := json.NewEncoder(w)
+		return enc.Encode(fields)
+	}))
+}
+
+// TerminalHandler writes each record as a single human-readable line:
+// t=... lvl=... msg="..." key=val key=val ...
+func TerminalHandler(w io.Writer) Handler {
+	return syncWrap(HandlerFunc(func(r *Record) error {
+		_, err := fmt.Fprintf(w, "t=%s lvl=%s msg=%q", r.Time.Format("2006-01-02T15:04:05.000Z07:00"), r.Lvl, r.Msg)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i+1 < len(r.Ctx); i += 2 {
+			if _, err := fmt.Fprintf(w, " %v=%v", r.Ctx[i], r.Ctx[i+1]); err != nil {
+				return err
+			}
+		}
+
+		_, err = fmt.Fprintln(w)
+		return err
+	}))
+}
@@ -0,0 +1,63 @@
+// Package logger is a small structured, key/value logger in the style of
+// log15: call sites pass a message plus alternating key/value context
+// (logger.Info("connected to peer", "peer", pinfo.ID, "addr", addr)) instead
+// of building formatted strings, so logs stay machine-parsable when shipped
+// to something like ELK or Loki.
+package logger
+
+import "time"
+
+// Lvl is a log level, ordered from most to least severe.
+type Lvl int
+
+const (
+	LvlCrit Lvl = iota
+	LvlError
+	LvlWarn
+	LvlInfo
+	LvlDebug
+)
+
+func (l Lvl) String() string {
+	switch l {
+	case LvlCrit:
+		return "crit"
+	case LvlError:
+		return "error"
+	case LvlWarn:
+		return "warn"
+	case LvlInfo:
+		return "info"
+	case LvlDebug:
+		return "debug"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is a single log event: a level, a message, and flat key/value
+// context (sticky context from New, followed by whatever was passed to the
+// call itself).
+type Record struct {
+	Time time.Time
+	Lvl  Lvl
+	Msg  string
+	Ctx  []interface{}
+}
+
+// Logger emits leveled, structured log records. New returns a child logger
+// that carries ctx on every record it emits, in addition to whatever ctx the
+// parent already carries, so call sites can bind identifiers once (a peer
+// ID, a message ID) and reuse the child logger for every related log line.
+type Logger interface {
+	New(ctx ...interface{}) Logger
+
+	Debug(msg string, ctx ...interface{})
+	Info(msg string, ctx ...interface{})
+	Warn(msg string, ctx ...interface{})
+	Error(msg string, ctx ...interface{})
+	Crit(msg string, ctx ...interface{})
+
+	// SetHandler swaps this logger's output handler.
+	SetHandler(h Handler)
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"os"
+	"time"
+)
+
+// logger is the concrete Logger implementation: a handler plus sticky
+// context inherited from (and extended by) New.
+type logger struct {
+	ctx []interface{}
+	h   Handler
+}
+
+// root is the package-level logger used by the package funcs below
+// (logger.Info, logger.Error, ...), mirroring log15's root logger.
+var root Logger = &logger{h: TerminalHandler(os.Stderr)}
+
+// New returns a child of the root logger carrying ctx as sticky context.
+func New(ctx ...interface{}) Logger {
+	return root.New(ctx...)
+}
+
+// SetHandler replaces the root logger's handler, e.g. to switch to JSON
+// output for shipping to ELK/Loki.
+func SetHandler(h Handler) {
+	root.SetHandler(h)
+}
+
+func Debug(msg string, ctx ...interface{}) { root.Debug(msg, ctx...) }
+func Info(msg string, ctx ...interface{})  { root.Info(msg, ctx...) }
+func Warn(msg string, ctx ...interface{})  { root.Warn(msg, ctx...) }
+func Error(msg string, ctx ...interface{}) { root.Error(msg, ctx...) }
+func Crit(msg string, ctx ...interface{})  { root.Crit(msg, ctx...) }
+
+func (l *logger) New(ctx ...interface{}) Logger {
+	return &logger{
+		ctx: append(append([]interface{}{}, l.ctx...), ctx...),
+		h:   l.h,
+	}
+}
+
+func (l *logger) SetHandler(h Handler) {
+	l.h = h
+}
+
+func (l *logger) write(lvl Lvl, msg string, ctx []interface{}) {
+	r := &Record{
+		Time: time.Now(),
+		Lvl:  lvl,
+		Msg:  msg,
+		Ctx:  append(append([]interface{}{}, l.ctx...), ctx...),
+	}
+
+	// note: a broken/unwritable log handler shouldn't panic the caller.
+	_ = l.h.Log(r)
+}
+
+func (l *logger) Debug(msg string, ctx ...interface{}) { l.write(LvlDebug, msg, ctx) }
+func (l *logger) Info(msg string, ctx ...interface{})  { l.write(LvlInfo, msg, ctx) }
+func (l *logger) Warn(msg string, ctx ...interface{})  { l.write(LvlWarn, msg, ctx) }
+func (l *logger) Error(msg string, ctx ...interface{}) { l.write(LvlError, msg, ctx) }
+func (l *logger) Crit(msg string, ctx ...interface{})  { l.write(LvlCrit, msg, ctx) }